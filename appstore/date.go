@@ -0,0 +1,93 @@
+package appstore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format of the date/time portion of Apple's formatted
+// date strings, e.g. "2019-05-31 16:32:50" out of
+// "2019-05-31 16:32:50 Etc/GMT". The trailing zone is an IANA zone name
+// (e.g. "Etc/GMT", "America/Los_Angeles"), which time.Parse's "MST"
+// directive can't match, so parseDate splits it off and drops it.
+const dateLayout = "2006-01-02 15:04:05"
+
+// parseDate prefers msField (milliseconds since epoch, as a decimal
+// string) and falls back to parsing field, since Apple has historically
+// sent the _ms form more reliably than the formatted one. field's trailing
+// zone name is dropped in favor of treating the timestamp as UTC, since
+// that's what Apple's primary (non-_pst) formatted fields already are.
+func parseDate(msField, field string) (time.Time, error) {
+	if msField != "" {
+		ms, err := strconv.ParseInt(msField, 10, 64)
+		if err == nil {
+			return time.UnixMilli(ms), nil
+		}
+	}
+
+	i := strings.LastIndex(field, " ")
+	if i == -1 {
+		return time.Time{}, fmt.Errorf("appstore: invalid date %q", field)
+	}
+	return time.ParseInLocation(dateLayout, field[:i], time.UTC)
+}
+
+// Time returns the receipt creation date.
+func (d ReceiptCreationDate) Time() (time.Time, error) {
+	return parseDate(d.CreationDateMS, d.CreationDate)
+}
+
+// Time returns the date and time the request was sent.
+func (d RequestDate) Time() (time.Time, error) {
+	return parseDate(d.RequestDateMS, d.RequestDate)
+}
+
+// Time returns the date and time the item was purchased.
+func (d PurchaseDate) Time() (time.Time, error) {
+	return parseDate(d.PurchaseDateMS, d.PurchaseDate)
+}
+
+// Time returns the date that begins the subscription period.
+func (d OriginalPurchaseDate) Time() (time.Time, error) {
+	return parseDate(d.OriginalPurchaseDateMS, d.OriginalPurchaseDate)
+}
+
+// Time returns the subscription expiration date.
+func (d ExpiresDate) Time() (time.Time, error) {
+	return parseDate(d.ExpiresDateMS, d.ExpiresDate)
+}
+
+// Time returns the date Apple customer support cancelled the transaction.
+func (d CancellationDate) Time() (time.Time, error) {
+	return parseDate(d.CancellationDateMS, d.CancellationDate)
+}
+
+// IsActive reports whether the in-app purchase's subscription period
+// covers now; it is false for non-renewing/consumable products, which
+// have no ExpiresDate.
+func (i InApp) IsActive(now time.Time) bool {
+	expires, err := i.ExpiresDate.Time()
+	if err != nil {
+		return false
+	}
+	return now.Before(expires)
+}
+
+// IsInTrial reports whether the in-app purchase was in its free trial
+// period.
+func (i InApp) IsInTrial() bool {
+	return i.IsTrialPeriod == "true" || i.IsTrialPeriod == "1"
+}
+
+// WillAutoRenew reports whether the subscription is set to auto-renew.
+func (p PendingRenewalInfo) WillAutoRenew() bool {
+	return p.SubscriptionAutoRenewStatus == "1"
+}
+
+// InBillingRetry reports whether Apple is currently retrying billing for
+// a failed renewal.
+func (p PendingRenewalInfo) InBillingRetry() bool {
+	return p.SubscriptionRetryFlag == "1"
+}
@@ -0,0 +1,62 @@
+package appstore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		msField string
+		field   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "ms field preferred",
+			msField: "1559320370000",
+			field:   "2019-05-31 16:32:50 Etc/GMT",
+			want:    time.UnixMilli(1559320370000),
+		},
+		{
+			name:  "falls back to formatted field with Etc/GMT zone",
+			field: "2019-05-31 16:32:50 Etc/GMT",
+			want:  time.Date(2019, 5, 31, 16, 32, 50, 0, time.UTC),
+		},
+		{
+			name:  "falls back to formatted field with multi-word IANA zone",
+			field: "2019-05-31 16:32:50 America/Los_Angeles",
+			want:  time.Date(2019, 5, 31, 16, 32, 50, 0, time.UTC),
+		},
+		{
+			name:    "non-numeric ms field falls back to formatted field",
+			msField: "not-a-number",
+			field:   "2019-05-31 16:32:50 Etc/GMT",
+			want:    time.Date(2019, 5, 31, 16, 32, 50, 0, time.UTC),
+		},
+		{
+			name:    "malformed field with no zone returns error",
+			field:   "2019-05-31",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.msField, tt.field)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDate() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDate() unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("parseDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package jws decodes and verifies the JWS (JSON Web Signature) payloads
+// that Apple uses both for App Store Server Notifications V2 and for the
+// App Store Server API. Apple signs these with ES256 and embeds the signing
+// certificate chain in the "x5c" header so the chain can be verified up to
+// Apple's root CA without an out-of-band key fetch.
+package jws
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrMalformedJWS is returned when the payload is not a three-part
+	// "header.payload.signature" compact JWS.
+	ErrMalformedJWS = errors.New("jws: malformed compact serialization")
+	// ErrUnsupportedAlgorithm is returned when the JWS header names an
+	// algorithm other than the ES256 Apple always uses.
+	ErrUnsupportedAlgorithm = errors.New("jws: unsupported alg, only ES256 is supported")
+	// ErrMissingCertificateChain is returned when the header has no x5c.
+	ErrMissingCertificateChain = errors.New("jws: header has no x5c certificate chain")
+	// ErrCertificateChainUntrusted is returned when the x5c chain does not
+	// chain up to one of the supplied root certificates.
+	ErrCertificateChainUntrusted = errors.New("jws: x5c certificate chain does not verify against the trusted roots")
+	// ErrInvalidSignature is returned when the JWS signature does not
+	// verify against the leaf certificate's public key.
+	ErrInvalidSignature = errors.New("jws: signature verification failed")
+)
+
+type header struct {
+	Algorithm string   `json:"alg"`
+	X5c       []string `json:"x5c"`
+}
+
+// JWS ES256 signatures are the raw r||s concatenation rather than the ASN.1
+// encoding x509 uses for ECDSA signatures, so verifyES256 decodes them by
+// splitting the byte slice rather than via asn1.Unmarshal.
+
+// Decode verifies a compact JWS (header.payload.signature) against roots
+// and unmarshals its payload into dest. It is used for both the outer
+// notification envelope and the doubly-nested signedTransactionInfo /
+// signedRenewalInfo payloads, since Apple signs all of them the same way.
+func Decode(token string, roots *x509.CertPool, dest interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrMalformedJWS
+	}
+
+	headerRaw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return err
+	}
+	payloadRaw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+
+	var h header
+	if err := json.Unmarshal(headerRaw, &h); err != nil {
+		return err
+	}
+	if h.Algorithm != "ES256" {
+		return ErrUnsupportedAlgorithm
+	}
+	if len(h.X5c) == 0 {
+		return ErrMissingCertificateChain
+	}
+
+	leaf, err := verifyChain(h.X5c, roots)
+	if err != nil {
+		return err
+	}
+
+	pub, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return ErrUnsupportedAlgorithm
+	}
+	if err := verifyES256(pub, parts[0]+"."+parts[1], sig); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(payloadRaw, dest)
+}
+
+// verifyChain parses the x5c certificates (leaf first, as Apple orders
+// them) and checks that they chain up to one of roots.
+func verifyChain(x5c []string, roots *x509.CertPool) (*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0, len(x5c))
+	for _, b64 := range x5c {
+		der, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := certs[0]
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, ErrCertificateChainUntrusted
+	}
+
+	return leaf, nil
+}
+
+func verifyES256(pub *ecdsa.PublicKey, signingInput string, sig []byte) error {
+	if len(sig) != 64 {
+		return ErrInvalidSignature
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	digest := sha256.Sum256([]byte(signingInput))
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
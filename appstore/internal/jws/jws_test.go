@@ -0,0 +1,196 @@
+package jws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testChain is a minimal self-signed root + leaf pair used to exercise
+// Decode's x5c verification without reaching out to Apple's real CAs.
+type testChain struct {
+	rootPool *x509.CertPool
+	leafKey  *ecdsa.PrivateKey
+	leafDER  []byte
+}
+
+func newTestChain(t *testing.T) testChain {
+	t.Helper()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, rootTmpl, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(rootCert)
+
+	return testChain{rootPool: pool, leafKey: leafKey, leafDER: leafDER}
+}
+
+type testPayload struct {
+	NotificationType string `json:"notificationType"`
+}
+
+// sign builds a compact ES256 JWS over payload, signed by chain's leaf key
+// and carrying chain's leaf certificate as the sole x5c entry.
+func (c testChain) sign(t *testing.T, payload interface{}) string {
+	t.Helper()
+
+	header := map[string]interface{}{
+		"alg": "ES256",
+		"x5c": []string{base64.StdEncoding.EncodeToString(c.leafDER)},
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.leafKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := append(leftPad(r, 32), leftPad(s, 32)...)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func leftPad(b *big.Int, size int) []byte {
+	buf := make([]byte, size)
+	raw := b.Bytes()
+	copy(buf[size-len(raw):], raw)
+	return buf
+}
+
+func TestDecode(t *testing.T) {
+	chain := newTestChain(t)
+	otherChain := newTestChain(t)
+
+	validToken := chain.sign(t, testPayload{NotificationType: "TEST"})
+
+	tamperedToken := func() string {
+		token := chain.sign(t, testPayload{NotificationType: "TEST"})
+		// Re-sign a different payload but keep the original signature,
+		// so the signature no longer matches the payload it's attached to.
+		parts := splitToken(token)
+		forged := chain.sign(t, testPayload{NotificationType: "FORGED"})
+		forgedParts := splitToken(forged)
+		return forgedParts[0] + "." + forgedParts[1] + "." + parts[2]
+	}()
+
+	tests := []struct {
+		name    string
+		token   string
+		roots   *x509.CertPool
+		wantErr error
+		wantVal string
+	}{
+		{
+			name:    "valid signature and trusted chain",
+			token:   validToken,
+			roots:   chain.rootPool,
+			wantVal: "TEST",
+		},
+		{
+			name:    "untrusted root",
+			token:   validToken,
+			roots:   otherChain.rootPool,
+			wantErr: ErrCertificateChainUntrusted,
+		},
+		{
+			name:    "tampered payload",
+			token:   tamperedToken,
+			roots:   chain.rootPool,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "malformed token",
+			token:   "not-a-jws",
+			roots:   chain.rootPool,
+			wantErr: ErrMalformedJWS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out testPayload
+			err := Decode(tt.token, tt.roots, &out)
+
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() unexpected error: %v", err)
+			}
+			if out.NotificationType != tt.wantVal {
+				t.Fatalf("NotificationType = %q, want %q", out.NotificationType, tt.wantVal)
+			}
+		})
+	}
+}
+
+func splitToken(token string) [3]string {
+	var parts [3]string
+	start := 0
+	idx := 0
+	for i := 0; i < len(token); i++ {
+		if token[i] == '.' {
+			parts[idx] = token[start:i]
+			idx++
+			start = i + 1
+		}
+	}
+	parts[idx] = token[start:]
+	return parts
+}
@@ -0,0 +1,336 @@
+package appstore
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+var (
+	// ErrInvalidCertificate is returned when rootCert cannot be parsed, or
+	// the receipt's signing certificate does not chain up to it.
+	ErrInvalidCertificate = errors.New("appstore: invalid root certificate")
+	// ErrInvalidSignature is returned when the receipt's PKCS#7 signature
+	// does not verify against its own signing certificate.
+	ErrInvalidSignature = errors.New("appstore: receipt signature is invalid")
+)
+
+// Receipt attribute type IDs, as documented at
+// https://developer.apple.com/library/archive/releasenotes/General/ValidateAppStoreReceipt/Chapters/ReceiptFields.html
+const (
+	attrTypeBundleID           = 2
+	attrTypeAppVersion         = 3
+	attrTypeOpaqueValue        = 4
+	attrTypeSHA1Hash           = 5
+	attrTypeCreationDate       = 12
+	attrTypeInApp              = 17
+	attrTypeOriginalAppVersion = 19
+	attrTypeExpirationDate     = 21
+
+	attrTypeInAppQuantity              = 1701
+	attrTypeInAppProductID             = 1702
+	attrTypeInAppTransactionID         = 1703
+	attrTypeInAppPurchaseDate          = 1704
+	attrTypeInAppOriginalTransactionID = 1705
+	attrTypeInAppOriginalPurchaseDate  = 1706
+	attrTypeInAppExpiresDate           = 1708
+	attrTypeInAppWebOrderLineItemID    = 1711
+	attrTypeInAppCancellationDate      = 1712
+	attrTypeInAppIsTrialPeriod         = 1713
+	attrTypeInAppIsInIntroOfferPeriod  = 1719
+)
+
+// ASN.1 OIDs used to resolve which signature algorithm a PKCS#7
+// SignerInfo's DigestAlgorithm/DigestEncryptionAlgorithm pair describes.
+var (
+	oidSHA1          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA1WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+)
+
+// receiptAttribute is one entry of the ASN.1 SET that makes up both the
+// top-level receipt payload and each nested in_app entry.
+type receiptAttribute struct {
+	Type    int
+	Version int
+	Value   []byte
+}
+
+// pkcs7ContentInfo mirrors the subset of PKCS#7 ContentInfo / SignedData
+// that Apple's receipts use; only the fields needed to reach the signed
+// content and verify the signature are modeled.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      pkcs7EncapsulatedContent
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	SignerInfos      asn1.RawValue
+}
+
+type pkcs7EncapsulatedContent struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7IssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+// LocalValidateReceipt parses a Base64-encoded PKCS#7 app receipt,
+// verifies it was signed by Apple (whose root certificate must be passed
+// in rootCert, PEM-encoded, to avoid bundling one here), and decodes its
+// attributes into a Receipt. It lets a caller validate a receipt entirely
+// on-device, without a round-trip to Apple's verifyReceipt endpoint.
+func LocalValidateReceipt(receiptBase64 string, rootCert []byte) (*Receipt, error) {
+	der, err := base64.StdEncoding.DecodeString(receiptBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	var outer pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &outer); err != nil {
+		return nil, err
+	}
+
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(outer.Content.Bytes, &signedData); err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	if _, err := asn1.Unmarshal(signedData.ContentInfo.Content.Bytes, &content); err != nil {
+		return nil, err
+	}
+
+	if err := verifyReceiptSignature(signedData, content, rootCert); err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseReceiptAttributeSet(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildReceipt(attrs)
+}
+
+// parseReceiptAttributeSet decodes data, a DER-encoded SET OF
+// receiptAttribute, by unwrapping the SET and then unmarshaling its
+// elements one at a time; encoding/asn1 can't decode directly into a Go
+// slice here because Apple encodes it with the SET tag rather than the
+// SEQUENCE tag Go's asn1 package assumes for slices.
+func parseReceiptAttributeSet(data []byte) ([]receiptAttribute, error) {
+	var set asn1.RawValue
+	if _, err := asn1.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	var attrs []receiptAttribute
+	rest := set.Bytes
+	for len(rest) > 0 {
+		var attr receiptAttribute
+		next, err := asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+		rest = next
+	}
+
+	return attrs, nil
+}
+
+// buildReceipt walks the attributes produced by parseReceiptAttributeSet
+// and populates a Receipt, recursing into nested in_app attribute sets.
+func buildReceipt(attrs []receiptAttribute) (*Receipt, error) {
+	receipt := &Receipt{}
+
+	for _, attr := range attrs {
+		switch attr.Type {
+		case attrTypeBundleID:
+			receipt.BundleID = decodeASN1String(attr.Value)
+		case attrTypeAppVersion:
+			receipt.ApplicationVersion = decodeASN1String(attr.Value)
+		case attrTypeOriginalAppVersion:
+			receipt.OriginalApplicationVersion = decodeASN1String(attr.Value)
+		case attrTypeCreationDate:
+			receipt.CreationDate = decodeASN1String(attr.Value)
+		case attrTypeExpirationDate, attrTypeOpaqueValue, attrTypeSHA1Hash:
+			// Not modeled on Receipt: expiration_date duplicates the
+			// per-in_app expires_date, and opaque_value/sha1_hash only
+			// exist for Apple's own GUID-hash validation.
+		case attrTypeInApp:
+			inAppAttrs, err := parseReceiptAttributeSet(attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			inApp, err := buildInApp(inAppAttrs)
+			if err != nil {
+				return nil, err
+			}
+			receipt.InApp = append(receipt.InApp, *inApp)
+		}
+	}
+
+	return receipt, nil
+}
+
+func buildInApp(attrs []receiptAttribute) (*InApp, error) {
+	inApp := &InApp{}
+
+	for _, attr := range attrs {
+		switch attr.Type {
+		case attrTypeInAppQuantity:
+			inApp.Quantity = decodeASN1Int(attr.Value)
+		case attrTypeInAppProductID:
+			inApp.ProductID = decodeASN1String(attr.Value)
+		case attrTypeInAppTransactionID:
+			inApp.TransactionID = decodeASN1String(attr.Value)
+		case attrTypeInAppOriginalTransactionID:
+			inApp.OriginalTransactionID = decodeASN1String(attr.Value)
+		case attrTypeInAppWebOrderLineItemID:
+			inApp.WebOrderLineItemID = decodeASN1String(attr.Value)
+		case attrTypeInAppIsTrialPeriod:
+			inApp.IsTrialPeriod = decodeASN1Bool(attr.Value)
+		case attrTypeInAppPurchaseDate:
+			inApp.PurchaseDate.PurchaseDate = decodeASN1String(attr.Value)
+		case attrTypeInAppOriginalPurchaseDate:
+			inApp.OriginalPurchaseDate.OriginalPurchaseDate = decodeASN1String(attr.Value)
+		case attrTypeInAppExpiresDate:
+			inApp.ExpiresDate.ExpiresDate = decodeASN1String(attr.Value)
+		case attrTypeInAppCancellationDate:
+			inApp.CancellationDate.CancellationDate = decodeASN1String(attr.Value)
+		case attrTypeInAppIsInIntroOfferPeriod:
+			inApp.IsInIntroOfferPeriod = decodeASN1Bool(attr.Value)
+		}
+	}
+
+	return inApp, nil
+}
+
+// decodeASN1String unwraps the UTF8String/IA5String that Apple encodes
+// each receipt attribute's value as; unrecognized encodings are returned
+// as-is so callers still see something rather than an empty string.
+func decodeASN1String(value []byte) string {
+	var s string
+	if _, err := asn1.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+func decodeASN1Int(value []byte) string {
+	var n int
+	if _, err := asn1.Unmarshal(value, &n); err == nil {
+		return strconv.Itoa(n)
+	}
+	return ""
+}
+
+func decodeASN1Bool(value []byte) string {
+	var b bool
+	if _, err := asn1.Unmarshal(value, &b); err == nil {
+		if b {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+// verifyReceiptSignature parses rootCert (PEM), the signing certificate
+// chain embedded in signedData.Certificates, checks the chain verifies up
+// to rootCert, and checks that the (sole, for Apple receipts) SignerInfo's
+// signature over content verifies against the leaf certificate.
+func verifyReceiptSignature(signedData pkcs7SignedData, content []byte, rootCert []byte) error {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootCert) {
+		return ErrInvalidCertificate
+	}
+
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil || len(certs) == 0 {
+		return ErrInvalidCertificate
+	}
+	leaf := certs[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return ErrInvalidCertificate
+	}
+
+	signerInfos, err := parseSignerInfos(signedData.SignerInfos.Bytes)
+	if err != nil || len(signerInfos) == 0 {
+		return ErrInvalidSignature
+	}
+
+	algo, err := signatureAlgorithmFor(signerInfos[0].DigestAlgorithm, signerInfos[0].DigestEncryptionAlgorithm)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if err := leaf.CheckSignature(algo, content, signerInfos[0].EncryptedDigest); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func parseSignerInfos(data []byte) ([]pkcs7SignerInfo, error) {
+	var infos []pkcs7SignerInfo
+	for rest := data; len(rest) > 0; {
+		var info pkcs7SignerInfo
+		next, err := asn1.Unmarshal(rest, &info)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+		rest = next
+	}
+	return infos, nil
+}
+
+func signatureAlgorithmFor(digestAlg, digestEncryptionAlg pkix.AlgorithmIdentifier) (x509.SignatureAlgorithm, error) {
+	switch {
+	case digestEncryptionAlg.Algorithm.Equal(oidSHA1WithRSA):
+		return x509.SHA1WithRSA, nil
+	case digestEncryptionAlg.Algorithm.Equal(oidSHA256WithRSA):
+		return x509.SHA256WithRSA, nil
+	case digestEncryptionAlg.Algorithm.Equal(oidRSAEncryption):
+		switch {
+		case digestAlg.Algorithm.Equal(oidSHA1):
+			return x509.SHA1WithRSA, nil
+		case digestAlg.Algorithm.Equal(oidSHA256):
+			return x509.SHA256WithRSA, nil
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, errors.New("appstore: unsupported receipt signature algorithm")
+}
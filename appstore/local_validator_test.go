@@ -0,0 +1,224 @@
+package appstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// derLength encodes n as a DER length octet (or octets, for the long form).
+func derLength(n int) []byte {
+	if n < 128 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for v := n; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return append([]byte{byte(0x80 | len(b))}, b...)
+}
+
+// derTLV wraps content in a DER tag-length-value using tag verbatim; it is
+// used to hand-build the PKCS#7 structures in local_validator.go that mix
+// SET tags and context-specific tags encoding/asn1 can't Marshal directly.
+func derTLV(tag byte, content []byte) []byte {
+	return append(append([]byte{tag}, derLength(len(content))...), content...)
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// receiptFixture builds a PKCS#7-signed app receipt DER structure by hand,
+// mirroring what local_validator.go expects to parse: a SignedData whose
+// encapsulated content is a SET OF receiptAttribute (with one nested SET OF
+// receiptAttribute for the single in_app entry), signed with an RSA leaf
+// certificate that chains to root.
+type receiptFixture struct {
+	receiptBase64 string
+	rootPEM       []byte
+}
+
+func buildReceiptFixture(t *testing.T) receiptFixture {
+	t.Helper()
+
+	rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})
+
+	leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, rootTmpl, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inAppAttrs := []receiptAttribute{
+		{Type: attrTypeInAppProductID, Version: 1, Value: mustMarshal(t, "com.example.widget")},
+		{Type: attrTypeInAppTransactionID, Version: 1, Value: mustMarshal(t, "1000000000000001")},
+		{Type: attrTypeInAppIsTrialPeriod, Version: 1, Value: mustMarshal(t, false)},
+		{Type: attrTypeInAppIsInIntroOfferPeriod, Version: 1, Value: mustMarshal(t, true)},
+	}
+	var inAppSetContent []byte
+	for _, a := range inAppAttrs {
+		inAppSetContent = append(inAppSetContent, mustMarshal(t, a)...)
+	}
+	inAppSet := derTLV(0x31, inAppSetContent)
+
+	topAttrs := []receiptAttribute{
+		{Type: attrTypeBundleID, Version: 1, Value: mustMarshal(t, "com.example.app")},
+		{Type: attrTypeAppVersion, Version: 1, Value: mustMarshal(t, "1.0")},
+		{Type: attrTypeInApp, Version: 1, Value: inAppSet},
+	}
+	var topSetContent []byte
+	for _, a := range topAttrs {
+		topSetContent = append(topSetContent, mustMarshal(t, a)...)
+	}
+	content := derTLV(0x31, topSetContent)
+
+	digest := sha256.Sum256(content)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, leafKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oidData := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	encapsulatedContentInfo := derTLV(0x30, append(mustMarshal(t, oidData), derTLV(0xA0, derTLV(0x04, content))...))
+
+	issuerAndSerial := derTLV(0x30, append(derTLV(0x30, nil), mustMarshal(t, big.NewInt(2))...))
+
+	digestAlg := mustMarshal(t, pkix.AlgorithmIdentifier{Algorithm: oidSHA256})
+	digestEncAlg := mustMarshal(t, pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA})
+	encryptedDigest := mustMarshal(t, sig)
+
+	var signerInfoContent []byte
+	signerInfoContent = append(signerInfoContent, mustMarshal(t, 1)...)
+	signerInfoContent = append(signerInfoContent, issuerAndSerial...)
+	signerInfoContent = append(signerInfoContent, digestAlg...)
+	signerInfoContent = append(signerInfoContent, digestEncAlg...)
+	signerInfoContent = append(signerInfoContent, encryptedDigest...)
+	signerInfo := derTLV(0x30, signerInfoContent)
+	signerInfos := derTLV(0x31, signerInfo)
+
+	certificates := derTLV(0xA0, leafDER)
+	digestAlgorithms := derTLV(0x31, nil)
+
+	var signedDataContent []byte
+	signedDataContent = append(signedDataContent, mustMarshal(t, 1)...)
+	signedDataContent = append(signedDataContent, digestAlgorithms...)
+	signedDataContent = append(signedDataContent, encapsulatedContentInfo...)
+	signedDataContent = append(signedDataContent, certificates...)
+	signedDataContent = append(signedDataContent, signerInfos...)
+	signedData := derTLV(0x30, signedDataContent)
+
+	oidSignedData := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	outerContent := append(mustMarshal(t, oidSignedData), derTLV(0xA0, signedData)...)
+	outer := derTLV(0x30, outerContent)
+
+	return receiptFixture{
+		receiptBase64: base64.StdEncoding.EncodeToString(outer),
+		rootPEM:       rootPEM,
+	}
+}
+
+func TestLocalValidateReceipt(t *testing.T) {
+	fixture := buildReceiptFixture(t)
+
+	receipt, err := LocalValidateReceipt(fixture.receiptBase64, fixture.rootPEM)
+	if err != nil {
+		t.Fatalf("LocalValidateReceipt() unexpected error: %v", err)
+	}
+
+	if receipt.BundleID != "com.example.app" {
+		t.Errorf("BundleID = %q, want %q", receipt.BundleID, "com.example.app")
+	}
+	if receipt.ApplicationVersion != "1.0" {
+		t.Errorf("ApplicationVersion = %q, want %q", receipt.ApplicationVersion, "1.0")
+	}
+	if len(receipt.InApp) != 1 {
+		t.Fatalf("len(InApp) = %d, want 1", len(receipt.InApp))
+	}
+	inApp := receipt.InApp[0]
+	if inApp.ProductID != "com.example.widget" {
+		t.Errorf("ProductID = %q, want %q", inApp.ProductID, "com.example.widget")
+	}
+	if inApp.IsTrialPeriod != "false" {
+		t.Errorf("IsTrialPeriod = %q, want %q", inApp.IsTrialPeriod, "false")
+	}
+	if inApp.IsInIntroOfferPeriod != "true" {
+		t.Errorf("IsInIntroOfferPeriod = %q, want %q", inApp.IsInIntroOfferPeriod, "true")
+	}
+}
+
+func TestLocalValidateReceiptUntrustedRoot(t *testing.T) {
+	fixture := buildReceiptFixture(t)
+	other := buildReceiptFixture(t)
+
+	_, err := LocalValidateReceipt(fixture.receiptBase64, other.rootPEM)
+	if err != ErrInvalidCertificate {
+		t.Fatalf("LocalValidateReceipt() error = %v, want %v", err, ErrInvalidCertificate)
+	}
+}
+
+func TestLocalValidateReceiptTamperedContent(t *testing.T) {
+	fixture := buildReceiptFixture(t)
+
+	der, err := base64.StdEncoding.DecodeString(fixture.receiptBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte of the bundle ID inside the signed content; the outer
+	// SEQUENCE/length header is left alone so the document still parses, but
+	// the signed content no longer matches the SignerInfo's signature.
+	tampered := append([]byte(nil), der...)
+	i := bytes.Index(tampered, []byte("com.example.app"))
+	if i < 0 {
+		t.Fatal("bundle ID not found in receipt fixture")
+	}
+	tampered[i] ^= 0xFF
+
+	_, err = LocalValidateReceipt(base64.StdEncoding.EncodeToString(tampered), fixture.rootPEM)
+	if err == nil {
+		t.Fatal("LocalValidateReceipt() error = nil, want an error for tampered content")
+	}
+}
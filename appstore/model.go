@@ -1,6 +1,9 @@
 package appstore
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 type numericString string
 
@@ -84,7 +87,8 @@ type (
 		OriginalTransactionID string `json:"original_transaction_id"`
 		WebOrderLineItemID    string `json:"web_order_line_item_id,omitempty"`
 
-		IsTrialPeriod string `json:"is_trial_period"`
+		IsTrialPeriod        string `json:"is_trial_period"`
+		IsInIntroOfferPeriod string `json:"is_in_intro_offer_period,omitempty"`
 		ExpiresDate
 
 		PurchaseDate
@@ -129,7 +133,7 @@ type (
 		Status             int                  `json:"status"`
 		Environment        Environment          `json:"environment"`
 		Receipt            Receipt              `json:"receipt"`
-		LatestReceiptInfo  []InApp              `json:"latest_receipt_info,omitempty"`
+		LatestReceiptInfo  flexibleInAppList    `json:"latest_receipt_info,omitempty"`
 		LatestReceipt      string               `json:"latest_receipt,omitempty"`
 		PendingRenewalInfo []PendingRenewalInfo `json:"pending_renewal_info,omitempty"`
 		IsRetryable        bool                 `json:"is-retryable,omitempty"`
@@ -138,19 +142,20 @@ type (
 	// The HttpStatusResponse struct contains the status code returned by the store
 	// Used as a workaround to detect when to hit the production appstore or sandbox appstore regardless of receipt type
 	StatusResponse struct {
-		Status int `json:"status"`
+		Status      int  `json:"status"`
+		IsRetryable bool `json:"is-retryable,omitempty"`
 	}
 
 	// IAPResponseForIOS6 is iOS 6 style receipt schema.
 	IAPResponseForIOS6 struct {
-		AutoRenewProductID     string         `json:"auto_renew_product_id"`
-		AutoRenewStatus        int            `json:"auto_renew_status"`
-		CancellationReason     string         `json:"cancellation_reason,omitempty"`
-		ExpirationIntent       string         `json:"expiration_intent,omitempty"`
-		IsInBillingRetryPeriod string         `json:"is_in_billing_retry_period,omitempty"`
-		LatestReceiptInfo      ReceiptForIOS6 `json:"latest_expired_receipt_info"`
-		Receipt                ReceiptForIOS6 `json:"receipt"`
-		Status                 int            `json:"status"`
+		AutoRenewProductID     string               `json:"auto_renew_product_id"`
+		AutoRenewStatus        int                  `json:"auto_renew_status"`
+		CancellationReason     string               `json:"cancellation_reason,omitempty"`
+		ExpirationIntent       string               `json:"expiration_intent,omitempty"`
+		IsInBillingRetryPeriod string               `json:"is_in_billing_retry_period,omitempty"`
+		LatestReceiptInfo      singleReceiptForIOS6 `json:"latest_expired_receipt_info"`
+		Receipt                ReceiptForIOS6       `json:"receipt"`
+		Status                 int                  `json:"status"`
 	}
 
 	ReceiptForIOS6 struct {
@@ -217,3 +222,66 @@ type (
 		LatestReceipt           string          `json:"latest_receipt"`              // 续费商品才有
 	}
 )
+
+// flexibleInAppList tolerates Apple's sandbox occasionally sending
+// latest_receipt_info as a single JSON object instead of an array.
+type flexibleInAppList []InApp
+
+// UnmarshalJSON decodes b as either a JSON array of InApp or a single
+// InApp object, normalizing the latter into a one-element slice.
+func (f *flexibleInAppList) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		*f = nil
+		return nil
+	}
+
+	if b[0] == '[' {
+		var list []InApp
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+		*f = list
+		return nil
+	}
+
+	var single InApp
+	if err := json.Unmarshal(b, &single); err != nil {
+		return err
+	}
+	*f = []InApp{single}
+	return nil
+}
+
+// singleReceiptForIOS6 tolerates Apple sending latest_expired_receipt_info
+// as a one-element array instead of a bare object; only the first element
+// is kept, matching the single-subscription shape ReceiptForIOS6 models.
+type singleReceiptForIOS6 ReceiptForIOS6
+
+// UnmarshalJSON decodes b as either a single ReceiptForIOS6 object or a
+// JSON array, taking its first element in the array case.
+func (s *singleReceiptForIOS6) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		*s = singleReceiptForIOS6{}
+		return nil
+	}
+
+	if b[0] == '[' {
+		var list []ReceiptForIOS6
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+		if len(list) > 0 {
+			*s = singleReceiptForIOS6(list[0])
+		}
+		return nil
+	}
+
+	var single ReceiptForIOS6
+	if err := json.Unmarshal(b, &single); err != nil {
+		return err
+	}
+	*s = singleReceiptForIOS6(single)
+	return nil
+}
@@ -0,0 +1,95 @@
+package appstore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFlexibleInAppListUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    flexibleInAppList
+		wantErr bool
+	}{
+		{
+			name: "array of one",
+			data: `[{"product_id":"com.example.widget"}]`,
+			want: flexibleInAppList{{ProductID: "com.example.widget"}},
+		},
+		{
+			name: "single object",
+			data: `{"product_id":"com.example.widget"}`,
+			want: flexibleInAppList{{ProductID: "com.example.widget"}},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: nil,
+		},
+		{
+			name: "empty array",
+			data: `[]`,
+			want: flexibleInAppList{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got flexibleInAppList
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("UnmarshalJSON() unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("UnmarshalJSON() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].ProductID != tt.want[i].ProductID {
+					t.Errorf("element %d ProductID = %q, want %q", i, got[i].ProductID, tt.want[i].ProductID)
+				}
+			}
+		})
+	}
+}
+
+func TestSingleReceiptForIOS6UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "single object",
+			data: `{"product_id":"com.example.widget"}`,
+			want: "com.example.widget",
+		},
+		{
+			name: "one-element array",
+			data: `[{"product_id":"com.example.widget"}]`,
+			want: "com.example.widget",
+		},
+		{
+			name: "empty array",
+			data: `[]`,
+			want: "",
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got singleReceiptForIOS6
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("UnmarshalJSON() unexpected error: %v", err)
+			}
+			if got.ProductID != tt.want {
+				t.Errorf("ProductID = %q, want %q", got.ProductID, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// Package notification handles App Store Server Notifications V2, the
+// server-to-server push counterpart to the polling appstore.IAPClient.Verify
+// flow. Apple POSTs a signedPayload JWS to a webhook URL the developer
+// configures in App Store Connect; this package verifies and decodes it.
+package notification
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/DonMillion/go-iap/appstore/internal/jws"
+)
+
+// NotificationType is the top-level "notificationType" Apple sends.
+type NotificationType string
+
+// All notification types documented at
+// https://developer.apple.com/documentation/appstoreservernotifications/notificationtype
+const (
+	NotificationTypeConsumptionRequest     NotificationType = "CONSUMPTION_REQUEST"
+	NotificationTypeDidChangeRenewalPref   NotificationType = "DID_CHANGE_RENEWAL_PREF"
+	NotificationTypeDidChangeRenewalStatus NotificationType = "DID_CHANGE_RENEWAL_STATUS"
+	NotificationTypeDidFailToRenew         NotificationType = "DID_FAIL_TO_RENEW"
+	NotificationTypeDidRenew               NotificationType = "DID_RENEW"
+	NotificationTypeExpired                NotificationType = "EXPIRED"
+	NotificationTypeGracePeriodExpired     NotificationType = "GRACE_PERIOD_EXPIRED"
+	NotificationTypeOfferRedeemed          NotificationType = "OFFER_REDEEMED"
+	NotificationTypePriceIncrease          NotificationType = "PRICE_INCREASE"
+	NotificationTypeRefund                 NotificationType = "REFUND"
+	NotificationTypeRefundDeclined         NotificationType = "REFUND_DECLINED"
+	NotificationTypeRenewalExtended        NotificationType = "RENEWAL_EXTENDED"
+	NotificationTypeRevoke                 NotificationType = "REVOKE"
+	NotificationTypeSubscribed             NotificationType = "SUBSCRIBED"
+	NotificationTypeOneTimeChargeRefund    NotificationType = "ONE_TIME_CHARGE_REFUND"
+	NotificationTypeTest                   NotificationType = "TEST"
+)
+
+// NotificationSubtype is the "subtype" Apple sends alongside some
+// NotificationTypes to disambiguate the event further.
+type NotificationSubtype string
+
+const (
+	SubtypeInitialBuy        NotificationSubtype = "INITIAL_BUY"
+	SubtypeResubscribe       NotificationSubtype = "RESUBSCRIBE"
+	SubtypeDowngrade         NotificationSubtype = "DOWNGRADE"
+	SubtypeUpgrade           NotificationSubtype = "UPGRADE"
+	SubtypeAutoRenewEnabled  NotificationSubtype = "AUTO_RENEW_ENABLED"
+	SubtypeAutoRenewDisabled NotificationSubtype = "AUTO_RENEW_DISABLED"
+	SubtypeVoluntary         NotificationSubtype = "VOLUNTARY"
+	SubtypeBillingRetry      NotificationSubtype = "BILLING_RETRY"
+	SubtypePriceIncrease     NotificationSubtype = "PRICE_INCREASE"
+	SubtypeGracePeriod       NotificationSubtype = "GRACE_PERIOD"
+	SubtypeBillingRecovery   NotificationSubtype = "BILLING_RECOVERY"
+	SubtypeFailure           NotificationSubtype = "FAILURE"
+)
+
+// ErrVerification wraps any failure to verify or decode an incoming
+// notification's signedPayload.
+var ErrVerification = errors.New("notification: failed to verify signedPayload")
+
+// ResponseBodyV2 is the raw JSON body Apple POSTs to the webhook.
+type ResponseBodyV2 struct {
+	SignedPayload string `json:"signedPayload"`
+}
+
+// Data carries the transaction/renewal info relevant to the notification,
+// each still separately JWS-signed by Apple.
+type Data struct {
+	AppAppleID            int64  `json:"appAppleId"`
+	BundleID              string `json:"bundleId"`
+	BundleVersion         string `json:"bundleVersion"`
+	Environment           string `json:"environment"`
+	SignedTransactionInfo string `json:"signedTransactionInfo"`
+	SignedRenewalInfo     string `json:"signedRenewalInfo"`
+}
+
+// DecodedPayload is the verified, decoded form of a signedPayload JWS.
+type DecodedPayload struct {
+	NotificationType NotificationType    `json:"notificationType"`
+	Subtype          NotificationSubtype `json:"subtype"`
+	NotificationUUID string              `json:"notificationUUID"`
+	Data             Data                `json:"data"`
+	Version          string              `json:"version"`
+	SignedDate       int64               `json:"signedDate"`
+}
+
+// TransactionInfo is the decoded form of Data.SignedTransactionInfo.
+type TransactionInfo struct {
+	TransactionID               string `json:"transactionId"`
+	OriginalTransactionID       string `json:"originalTransactionId"`
+	WebOrderLineItemID          string `json:"webOrderLineItemId"`
+	BundleID                    string `json:"bundleId"`
+	ProductID                   string `json:"productId"`
+	SubscriptionGroupIdentifier string `json:"subscriptionGroupIdentifier"`
+	PurchaseDate                int64  `json:"purchaseDate"`
+	OriginalPurchaseDate        int64  `json:"originalPurchaseDate"`
+	ExpiresDate                 int64  `json:"expiresDate"`
+	Quantity                    int    `json:"quantity"`
+	Type                        string `json:"type"`
+	InAppOwnershipType          string `json:"inAppOwnershipType"`
+	SignedDate                  int64  `json:"signedDate"`
+	Environment                 string `json:"environment"`
+}
+
+// RenewalInfo is the decoded form of Data.SignedRenewalInfo.
+type RenewalInfo struct {
+	OriginalTransactionID  string `json:"originalTransactionId"`
+	AutoRenewProductID     string `json:"autoRenewProductId"`
+	ProductID              string `json:"productId"`
+	AutoRenewStatus        int    `json:"autoRenewStatus"`
+	IsInBillingRetryPeriod bool   `json:"isInBillingRetryPeriod"`
+	ExpirationIntent       int    `json:"expirationIntent"`
+	GracePeriodExpiresDate int64  `json:"gracePeriodExpiresDate"`
+	SignedDate             int64  `json:"signedDate"`
+	Environment            string `json:"environment"`
+}
+
+// DecodeNotification verifies the signedPayload against roots and decodes
+// it and its nested signedTransactionInfo / signedRenewalInfo, when
+// present, into a DecodedPayload plus the two inner structs.
+func DecodeNotification(signedPayload string, roots *x509.CertPool) (*DecodedPayload, *TransactionInfo, *RenewalInfo, error) {
+	var payload DecodedPayload
+	if err := jws.Decode(signedPayload, roots, &payload); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var txInfo *TransactionInfo
+	if payload.Data.SignedTransactionInfo != "" {
+		txInfo = new(TransactionInfo)
+		if err := jws.Decode(payload.Data.SignedTransactionInfo, roots, txInfo); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var renewalInfo *RenewalInfo
+	if payload.Data.SignedRenewalInfo != "" {
+		renewalInfo = new(RenewalInfo)
+		if err := jws.Decode(payload.Data.SignedRenewalInfo, roots, renewalInfo); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return &payload, txInfo, renewalInfo, nil
+}
+
+// Handler is invoked once a notification of its registered NotificationType
+// has been verified and decoded.
+type Handler func(payload *DecodedPayload, tx *TransactionInfo, renewal *RenewalInfo) error
+
+// Dispatcher routes verified notifications to a Handler registered per
+// NotificationType, mirroring how appstore.IAPClient.Verify is the single
+// entry point for the polling side.
+type Dispatcher struct {
+	Roots    *x509.CertPool
+	handlers map[NotificationType]Handler
+}
+
+// NewDispatcher creates a Dispatcher that verifies incoming payloads
+// against roots, Apple's root CA (or a test root, in non-production use).
+func NewDispatcher(roots *x509.CertPool) *Dispatcher {
+	return &Dispatcher{
+		Roots:    roots,
+		handlers: make(map[NotificationType]Handler),
+	}
+}
+
+// HandleFunc registers handler to be called for notifications of type t,
+// replacing any previously registered handler for that type.
+func (d *Dispatcher) HandleFunc(t NotificationType, handler Handler) {
+	d.handlers[t] = handler
+}
+
+// Dispatch verifies and decodes signedPayload and, if a handler is
+// registered for its notificationType, invokes it. It returns
+// ErrVerification if the payload fails JWS verification, and otherwise
+// whatever error the handler returns.
+func (d *Dispatcher) Dispatch(signedPayload string) error {
+	payload, tx, renewal, err := DecodeNotification(signedPayload, d.Roots)
+	if err != nil {
+		return ErrVerification
+	}
+
+	handler, ok := d.handlers[payload.NotificationType]
+	if !ok {
+		return nil
+	}
+	return handler(payload, tx, renewal)
+}
+
+// ServeHTTP implements http.Handler so a Dispatcher can be mounted
+// directly as the webhook endpoint registered in App Store Connect.
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var rb ResponseBodyV2
+	if err := json.Unmarshal(body, &rb); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := d.Dispatch(rb.SignedPayload); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
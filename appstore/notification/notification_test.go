@@ -0,0 +1,241 @@
+package notification
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testChain is a minimal self-signed root + leaf pair, built the same way
+// as appstore/internal/jws's own test chain, used here to sign
+// DecodedPayload fixtures for Dispatcher tests.
+type testChain struct {
+	rootPool *x509.CertPool
+	leafKey  *ecdsa.PrivateKey
+	leafDER  []byte
+}
+
+func newTestChain(t *testing.T) testChain {
+	t.Helper()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, rootTmpl, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(rootCert)
+
+	return testChain{rootPool: pool, leafKey: leafKey, leafDER: leafDER}
+}
+
+func (c testChain) sign(t *testing.T, payload interface{}) string {
+	t.Helper()
+
+	header := map[string]interface{}{
+		"alg": "ES256",
+		"x5c": []string{base64.StdEncoding.EncodeToString(c.leafDER)},
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.leafKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := append(leftPad(r, 32), leftPad(s, 32)...)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func leftPad(b *big.Int, size int) []byte {
+	buf := make([]byte, size)
+	raw := b.Bytes()
+	copy(buf[size-len(raw):], raw)
+	return buf
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	chain := newTestChain(t)
+
+	var gotType NotificationType
+	dispatcher := NewDispatcher(chain.rootPool)
+	dispatcher.HandleFunc(NotificationTypeTest, func(payload *DecodedPayload, tx *TransactionInfo, renewal *RenewalInfo) error {
+		gotType = payload.NotificationType
+		return nil
+	})
+
+	token := chain.sign(t, DecodedPayload{NotificationType: NotificationTypeTest})
+	if err := dispatcher.Dispatch(token); err != nil {
+		t.Fatalf("Dispatch() unexpected error: %v", err)
+	}
+	if gotType != NotificationTypeTest {
+		t.Errorf("handler saw NotificationType = %q, want %q", gotType, NotificationTypeTest)
+	}
+}
+
+func TestDispatcher_DispatchUnregisteredType(t *testing.T) {
+	chain := newTestChain(t)
+
+	dispatcher := NewDispatcher(chain.rootPool)
+	token := chain.sign(t, DecodedPayload{NotificationType: NotificationTypeRefund})
+
+	if err := dispatcher.Dispatch(token); err != nil {
+		t.Fatalf("Dispatch() unexpected error for unregistered type: %v", err)
+	}
+}
+
+func TestDispatcher_DispatchVerificationFailure(t *testing.T) {
+	chain := newTestChain(t)
+	other := newTestChain(t)
+
+	dispatcher := NewDispatcher(other.rootPool)
+	token := chain.sign(t, DecodedPayload{NotificationType: NotificationTypeTest})
+
+	if err := dispatcher.Dispatch(token); err != ErrVerification {
+		t.Fatalf("Dispatch() error = %v, want %v", err, ErrVerification)
+	}
+}
+
+func TestDispatcher_DispatchHandlerError(t *testing.T) {
+	chain := newTestChain(t)
+	wantErr := errors.New("boom")
+
+	dispatcher := NewDispatcher(chain.rootPool)
+	dispatcher.HandleFunc(NotificationTypeTest, func(payload *DecodedPayload, tx *TransactionInfo, renewal *RenewalInfo) error {
+		return wantErr
+	})
+
+	token := chain.sign(t, DecodedPayload{NotificationType: NotificationTypeTest})
+	if err := dispatcher.Dispatch(token); err != wantErr {
+		t.Fatalf("Dispatch() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestDispatcher_ServeHTTP(t *testing.T) {
+	chain := newTestChain(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		register   bool
+		handlerErr error
+		wantStatus int
+	}{
+		{
+			name:       "method not allowed",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "bad JSON body",
+			method:     http.MethodPost,
+			body:       `not json`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unregistered type is a no-op 200",
+			method:     http.MethodPost,
+			body:       mustResponseBody(t, chain.sign(t, DecodedPayload{NotificationType: NotificationTypeRefund})),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "handler error maps to 401",
+			method:     http.MethodPost,
+			body:       mustResponseBody(t, chain.sign(t, DecodedPayload{NotificationType: NotificationTypeTest})),
+			register:   true,
+			handlerErr: errors.New("rejected"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "handler success is 200",
+			method:     http.MethodPost,
+			body:       mustResponseBody(t, chain.sign(t, DecodedPayload{NotificationType: NotificationTypeTest})),
+			register:   true,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcher(chain.rootPool)
+			if tt.register {
+				dispatcher.HandleFunc(NotificationTypeTest, func(payload *DecodedPayload, tx *TransactionInfo, renewal *RenewalInfo) error {
+					return tt.handlerErr
+				})
+			}
+
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			dispatcher.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func mustResponseBody(t *testing.T, signedPayload string) string {
+	t.Helper()
+	b, err := json.Marshal(ResponseBodyV2{SignedPayload: signedPayload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
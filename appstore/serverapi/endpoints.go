@@ -0,0 +1,193 @@
+package serverapi
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// TransactionInfoResponse is the response to GetTransactionInfo.
+type TransactionInfoResponse struct {
+	SignedTransactionInfo string `json:"signedTransactionInfo"`
+}
+
+// SubscriptionGroupIdentifierItem is one subscription group's status, as
+// returned by GetAllSubscriptionStatuses.
+type SubscriptionGroupIdentifierItem struct {
+	SubscriptionGroupIdentifier string                 `json:"subscriptionGroupIdentifier"`
+	LastTransactions            []LastTransactionsItem `json:"lastTransactions"`
+}
+
+// LastTransactionsItem pairs a transaction/renewal JWS pair with the
+// subscription status Apple computed for it.
+type LastTransactionsItem struct {
+	OriginalTransactionID string `json:"originalTransactionId"`
+	Status                int    `json:"status"`
+	SignedRenewalInfo     string `json:"signedRenewalInfo"`
+	SignedTransactionInfo string `json:"signedTransactionInfo"`
+}
+
+// StatusResponse is the response to GetAllSubscriptionStatuses.
+type StatusResponse struct {
+	Environment string                            `json:"environment"`
+	BundleID    string                            `json:"bundleId"`
+	AppAppleID  int64                             `json:"appAppleId"`
+	Data        []SubscriptionGroupIdentifierItem `json:"data"`
+}
+
+// OrderLookupResponse is the response to LookUpOrderID.
+type OrderLookupResponse struct {
+	Status             int      `json:"status"`
+	SignedTransactions []string `json:"signedTransactions"`
+}
+
+// HistoryResponse is one page of the response to GetTransactionHistory.
+type HistoryResponse struct {
+	Revision           string   `json:"revision"`
+	HasMore            bool     `json:"hasMore"`
+	BundleID           string   `json:"bundleId"`
+	AppAppleID         int64    `json:"appAppleId"`
+	Environment        string   `json:"environment"`
+	SignedTransactions []string `json:"signedTransactions"`
+}
+
+// RefundHistoryResponse is one page of the response to GetRefundHistory.
+type RefundHistoryResponse struct {
+	Revision           string   `json:"revision"`
+	HasMore            bool     `json:"hasMore"`
+	SignedTransactions []string `json:"signedTransactions"`
+}
+
+// ConsumptionRequest is the body sent to SendConsumptionInformation,
+// describing a consumable's usage in response to a CONSUMPTION_REQUEST
+// notification.
+type ConsumptionRequest struct {
+	CustomerConsented        bool   `json:"customerConsented"`
+	ConsumptionStatus        int    `json:"consumptionStatus"`
+	Platform                 int    `json:"platform"`
+	SampleContentProvided    bool   `json:"sampleContentProvided"`
+	DeliveryStatus           int    `json:"deliveryStatus"`
+	AppAccountToken          string `json:"appAccountToken,omitempty"`
+	AccountTenure            int    `json:"accountTenure"`
+	PlayTime                 int    `json:"playTime"`
+	LifetimeDollarsRefunded  int    `json:"lifetimeDollarsRefunded"`
+	LifetimeDollarsPurchased int    `json:"lifetimeDollarsPurchased"`
+	UserStatus               int    `json:"userStatus"`
+}
+
+// ExtendRenewalDateRequest is the body sent to
+// ExtendSubscriptionRenewalDate.
+type ExtendRenewalDateRequest struct {
+	ExtendByDays      int    `json:"extendByDays"`
+	ExtendReasonCode  int    `json:"extendReasonCode"`
+	RequestIdentifier string `json:"requestIdentifier"`
+}
+
+// ExtendRenewalDateResponse is the response to
+// ExtendSubscriptionRenewalDate.
+type ExtendRenewalDateResponse struct {
+	OriginalTransactionID string `json:"originalTransactionId"`
+	WebOrderLineItemID    string `json:"webOrderLineItemId"`
+	Success               bool   `json:"success"`
+	EffectiveDate         int64  `json:"effectiveDate"`
+}
+
+// GetTransactionInfo returns the signed transaction information for a
+// single transaction ID.
+func (c *Client) GetTransactionInfo(transactionID string) (*TransactionInfoResponse, error) {
+	var out TransactionInfoResponse
+	if err := c.do("GET", "/inApps/v1/transactions/"+url.PathEscape(transactionID), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAllSubscriptionStatuses returns the status of all subscriptions in
+// the subscription group that transactionID belongs to.
+func (c *Client) GetAllSubscriptionStatuses(transactionID string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do("GET", "/inApps/v1/subscriptions/"+url.PathEscape(transactionID), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LookUpOrderID returns the transactions associated with orderID, Apple's
+// order number as shown to the customer on their receipt/invoice.
+func (c *Client) LookUpOrderID(orderID string) (*OrderLookupResponse, error) {
+	var out OrderLookupResponse
+	if err := c.do("GET", "/inApps/v1/lookup/"+url.PathEscape(orderID), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTransactionHistory returns one page of transaction history for
+// transactionID. sort should be "ASCENDING" or "DESCENDING"; pass
+// revision from a prior HistoryResponse.Revision to fetch the next page,
+// or "" for the first page.
+func (c *Client) GetTransactionHistory(transactionID, sort, revision string) (*HistoryResponse, error) {
+	q := url.Values{}
+	if sort != "" {
+		q.Set("sort", sort)
+	}
+	if revision != "" {
+		q.Set("revision", revision)
+	}
+
+	path := "/inApps/v1/history/" + url.PathEscape(transactionID)
+	if encoded := q.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+
+	var out HistoryResponse
+	if err := c.do("GET", path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRefundHistory returns one page of refunded transactions for
+// transactionID. Pass revision from a prior RefundHistoryResponse to
+// fetch the next page, or "" for the first page.
+func (c *Client) GetRefundHistory(transactionID, revision string) (*RefundHistoryResponse, error) {
+	path := "/inApps/v2/refund/lookup/" + url.PathEscape(transactionID)
+	if revision != "" {
+		path += "?revision=" + url.QueryEscape(revision)
+	}
+
+	var out RefundHistoryResponse
+	if err := c.do("GET", path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SendConsumptionInformation reports a consumable's usage to Apple in
+// response to a CONSUMPTION_REQUEST notification.
+func (c *Client) SendConsumptionInformation(transactionID string, req ConsumptionRequest) error {
+	return c.do("PUT", "/inApps/v1/transactions/consumption/"+url.PathEscape(transactionID), req, nil)
+}
+
+// ExtendSubscriptionRenewalDate extends the renewal date of a single
+// subscription identified by originalTransactionID.
+func (c *Client) ExtendSubscriptionRenewalDate(originalTransactionID string, req ExtendRenewalDateRequest) (*ExtendRenewalDateResponse, error) {
+	var out ExtendRenewalDateResponse
+	path := fmt.Sprintf("/inApps/v1/subscriptions/extend/%s", url.PathEscape(originalTransactionID))
+	if err := c.do("PUT", path, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DecodeTransactionInfo verifies and decodes a signedTransactionInfo JWS
+// into a notification.TransactionInfo-shaped struct supplied by the
+// caller via dest, using the client's configured root certificate.
+func (c *Client) DecodeTransactionInfo(signedTransactionInfo string, dest interface{}) error {
+	return c.decodeSigned(signedTransactionInfo, dest)
+}
+
+// DecodeRenewalInfo verifies and decodes a signedRenewalInfo JWS into
+// dest, using the client's configured root certificate.
+func (c *Client) DecodeRenewalInfo(signedRenewalInfo string, dest interface{}) error {
+	return c.decodeSigned(signedRenewalInfo, dest)
+}
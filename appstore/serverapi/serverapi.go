@@ -0,0 +1,231 @@
+// Package serverapi implements Apple's App Store Server API, the
+// JWT-authenticated REST alternative to the shared-secret verifyReceipt
+// flow in the parent appstore package. Unlike verifyReceipt, it is
+// addressed by transaction ID rather than by POSTing the whole receipt,
+// and its responses carry JWS-signed transactions decoded the same way
+// as the appstore/notification package decodes Server Notifications V2.
+package serverapi
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DonMillion/go-iap/appstore/internal/jws"
+)
+
+const (
+	// ProductionHost is the App Store Server API host for production.
+	ProductionHost = "https://api.storekit.apple.com"
+	// SandboxHost is the App Store Server API host for sandbox testing.
+	SandboxHost = "https://api.storekit-sandbox.apple.com"
+
+	// tokenTTL is kept comfortably under Apple's 60 minute maximum so a
+	// token is always refreshed before it expires mid-request.
+	tokenTTL = 55 * time.Minute
+)
+
+// ErrInvalidPrivateKey is returned when the .p8 private key passed to
+// NewClient cannot be parsed as an ES256 (P-256) key.
+var ErrInvalidPrivateKey = errors.New("serverapi: invalid ES256 private key")
+
+// StoreConfig holds the credentials and environment needed to talk to the
+// App Store Server API: an ES256 JWT signed with the issuer/key pair
+// downloaded from App Store Connect, scoped to one bundle ID.
+type StoreConfig struct {
+	// KeyContent is the contents of the .p8 private key downloaded from
+	// App Store Connect (Users and Access > Keys).
+	KeyContent []byte
+	KeyID      string
+	IssuerID   string
+	BundleID   string
+	// Sandbox selects SandboxHost instead of ProductionHost.
+	Sandbox bool
+	// RootCert is the PEM-encoded root CA used to verify the x5c chain
+	// on signed transactions embedded in responses.
+	RootCert []byte
+}
+
+// Client is an App Store Server API client for a single StoreConfig.
+type Client struct {
+	config  StoreConfig
+	host    string
+	key     *ecdsa.PrivateKey
+	roots   *x509.CertPool
+	httpCli *http.Client
+
+	mu          sync.Mutex
+	cachedToken string
+	tokenIssue  time.Time
+}
+
+// NewClient creates a Client for config, parsing its private key and root
+// certificate up front so later calls fail fast on bad credentials.
+func NewClient(config StoreConfig) (*Client, error) {
+	block, _ := pem.Decode(config.KeyContent)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	roots := x509.NewCertPool()
+	if len(config.RootCert) > 0 && !roots.AppendCertsFromPEM(config.RootCert) {
+		return nil, errors.New("serverapi: invalid root certificate")
+	}
+
+	host := ProductionHost
+	if config.Sandbox {
+		host = SandboxHost
+	}
+
+	return &Client{
+		config:  config,
+		host:    host,
+		key:     key,
+		roots:   roots,
+		httpCli: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// token returns a cached, still-valid signed JWT, minting a new one if
+// none is cached or the cached one is older than tokenTTL.
+func (c *Client) token() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cachedToken != "" && time.Since(c.tokenIssue) < tokenTTL {
+		return c.cachedToken, nil
+	}
+
+	now := time.Now()
+	header := map[string]interface{}{
+		"alg": "ES256",
+		"kid": c.config.KeyID,
+		"typ": "JWT",
+	}
+	claims := map[string]interface{}{
+		"iss": c.config.IssuerID,
+		"iat": now.Unix(),
+		"exp": now.Add(tokenTTL).Unix(),
+		"aud": "appstoreconnect-v1",
+		"bid": c.config.BundleID,
+	}
+
+	signingInput, err := encodeSigningInput(header, claims)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := append(leftPad(r, 32), leftPad(s, 32)...)
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+	c.cachedToken = token
+	c.tokenIssue = now
+	return token, nil
+}
+
+func encodeSigningInput(header, claims map[string]interface{}) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c), nil
+}
+
+func leftPad(b *big.Int, size int) []byte {
+	buf := make([]byte, size)
+	bytes := b.Bytes()
+	copy(buf[size-len(bytes):], bytes)
+	return buf
+}
+
+// do issues a request against the App Store Server API, attaching the
+// bearer token and decoding a JSON response into result.
+func (c *Client) do(method, path string, body interface{}, result interface{}) error {
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+
+	var reqBody *bytes.Buffer
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewBuffer(b)
+	} else {
+		reqBody = bytes.NewBuffer(nil)
+	}
+
+	req, err := http.NewRequest(method, c.host+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpCli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			ErrorCode    int    `json:"errorCode"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		if err := json.Unmarshal(buf, &apiErr); err == nil && apiErr.ErrorMessage != "" {
+			return fmt.Errorf("serverapi: %s (status %d, code %d)", apiErr.ErrorMessage, resp.StatusCode, apiErr.ErrorCode)
+		}
+		return fmt.Errorf("serverapi: unexpected status %d", resp.StatusCode)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(buf, result)
+}
+
+// decodeSigned verifies and decodes a signedTransactionInfo /
+// signedRenewalInfo JWS using the same certificate-chain verification the
+// appstore/notification package uses.
+func (c *Client) decodeSigned(signed string, dest interface{}) error {
+	return jws.Decode(signed, c.roots, dest)
+}
@@ -0,0 +1,347 @@
+package serverapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, host string) *Client {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	c, err := NewClient(StoreConfig{
+		KeyContent: keyPEM,
+		KeyID:      "test-kid",
+		IssuerID:   "test-issuer",
+		BundleID:   "com.example.app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.host = host
+	return c
+}
+
+func TestToken(t *testing.T) {
+	c := newTestClient(t, "")
+
+	first, err := c.token()
+	if err != nil {
+		t.Fatalf("token() unexpected error: %v", err)
+	}
+	second, err := c.token()
+	if err != nil {
+		t.Fatalf("token() unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("token() re-minted within TTL: %q != %q", first, second)
+	}
+
+	c.tokenIssue = time.Now().Add(-tokenTTL - time.Second)
+	third, err := c.token()
+	if err != nil {
+		t.Fatalf("token() unexpected error: %v", err)
+	}
+	if third == second {
+		t.Error("token() did not re-mint after TTL expiry")
+	}
+}
+
+func TestDo_ErrorFormatting(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantSubstr string
+	}{
+		{
+			name:       "apiErr body",
+			statusCode: http.StatusBadRequest,
+			body:       `{"errorCode":4000006,"errorMessage":"The transaction ID could not be found."}`,
+			wantSubstr: "The transaction ID could not be found.",
+		},
+		{
+			name:       "non-JSON body falls back to generic status",
+			statusCode: http.StatusInternalServerError,
+			body:       `not json`,
+			wantSubstr: "unexpected status 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := newTestClient(t, server.URL)
+			_, err := c.GetTransactionInfo("1000000000000001")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantSubstr) {
+				t.Errorf("error = %q, want substring %q", err.Error(), tt.wantSubstr)
+			}
+		})
+	}
+}
+
+func TestEndpoints_PathAndQuery(t *testing.T) {
+	var gotMethod, gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL)
+
+	tests := []struct {
+		name       string
+		call       func() error
+		wantMethod string
+		wantPath   string
+		wantQuery  string
+	}{
+		{
+			name:       "GetTransactionInfo",
+			call:       func() error { _, err := c.GetTransactionInfo("1000000000000001"); return err },
+			wantMethod: "GET",
+			wantPath:   "/inApps/v1/transactions/1000000000000001",
+		},
+		{
+			name:       "GetAllSubscriptionStatuses",
+			call:       func() error { _, err := c.GetAllSubscriptionStatuses("1000000000000001"); return err },
+			wantMethod: "GET",
+			wantPath:   "/inApps/v1/subscriptions/1000000000000001",
+		},
+		{
+			name:       "LookUpOrderID",
+			call:       func() error { _, err := c.LookUpOrderID("W002182"); return err },
+			wantMethod: "GET",
+			wantPath:   "/inApps/v1/lookup/W002182",
+		},
+		{
+			name:       "GetTransactionHistory no paging",
+			call:       func() error { _, err := c.GetTransactionHistory("1000000000000001", "", ""); return err },
+			wantMethod: "GET",
+			wantPath:   "/inApps/v1/history/1000000000000001",
+		},
+		{
+			name: "GetTransactionHistory with sort and revision",
+			call: func() error {
+				_, err := c.GetTransactionHistory("1000000000000001", "ASCENDING", "rev-1")
+				return err
+			},
+			wantMethod: "GET",
+			wantPath:   "/inApps/v1/history/1000000000000001",
+			wantQuery:  "revision=rev-1&sort=ASCENDING",
+		},
+		{
+			name:       "GetRefundHistory no revision",
+			call:       func() error { _, err := c.GetRefundHistory("1000000000000001", ""); return err },
+			wantMethod: "GET",
+			wantPath:   "/inApps/v2/refund/lookup/1000000000000001",
+		},
+		{
+			name:       "GetRefundHistory with revision",
+			call:       func() error { _, err := c.GetRefundHistory("1000000000000001", "rev-1"); return err },
+			wantMethod: "GET",
+			wantPath:   "/inApps/v2/refund/lookup/1000000000000001",
+			wantQuery:  "revision=rev-1",
+		},
+		{
+			name: "SendConsumptionInformation",
+			call: func() error {
+				return c.SendConsumptionInformation("1000000000000001", ConsumptionRequest{})
+			},
+			wantMethod: "PUT",
+			wantPath:   "/inApps/v1/transactions/consumption/1000000000000001",
+		},
+		{
+			name: "ExtendSubscriptionRenewalDate",
+			call: func() error {
+				_, err := c.ExtendSubscriptionRenewalDate("1000000000000001", ExtendRenewalDateRequest{})
+				return err
+			},
+			wantMethod: "PUT",
+			wantPath:   "/inApps/v1/subscriptions/extend/1000000000000001",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Errorf("method = %q, want %q", gotMethod, tt.wantMethod)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if gotQuery != tt.wantQuery {
+				t.Errorf("query = %q, want %q", gotQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+// testChain is a minimal self-signed root + leaf pair used to build
+// signedTransactionInfo/signedRenewalInfo fixtures for decodeSigned,
+// mirroring appstore/internal/jws's own test chain.
+type testChain struct {
+	rootPEM []byte
+	leafKey *ecdsa.PrivateKey
+	leafDER []byte
+}
+
+func newTestChain(t *testing.T) testChain {
+	t.Helper()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, rootTmpl, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testChain{rootPEM: rootPEM, leafKey: leafKey, leafDER: leafDER}
+}
+
+func (c testChain) sign(t *testing.T, payload interface{}) string {
+	t.Helper()
+
+	header := map[string]interface{}{
+		"alg": "ES256",
+		"x5c": []string{base64.StdEncoding.EncodeToString(c.leafDER)},
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.leafKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := append(leftPadBig(r, 32), leftPadBig(s, 32)...)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func leftPadBig(b *big.Int, size int) []byte {
+	buf := make([]byte, size)
+	raw := b.Bytes()
+	copy(buf[size-len(raw):], raw)
+	return buf
+}
+
+func TestDecodeTransactionAndRenewalInfo(t *testing.T) {
+	chain := newTestChain(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	c, err := NewClient(StoreConfig{
+		KeyContent: keyPEM,
+		KeyID:      "test-kid",
+		IssuerID:   "test-issuer",
+		BundleID:   "com.example.app",
+		RootCert:   chain.rootPEM,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txToken := chain.sign(t, map[string]string{"transactionId": "1000000000000001"})
+	var tx struct {
+		TransactionID string `json:"transactionId"`
+	}
+	if err := c.DecodeTransactionInfo(txToken, &tx); err != nil {
+		t.Fatalf("DecodeTransactionInfo() unexpected error: %v", err)
+	}
+	if tx.TransactionID != "1000000000000001" {
+		t.Errorf("TransactionID = %q, want %q", tx.TransactionID, "1000000000000001")
+	}
+
+	renewalToken := chain.sign(t, map[string]string{"autoRenewProductId": "com.example.renewal"})
+	var renewal struct {
+		AutoRenewProductID string `json:"autoRenewProductId"`
+	}
+	if err := c.DecodeRenewalInfo(renewalToken, &renewal); err != nil {
+		t.Fatalf("DecodeRenewalInfo() unexpected error: %v", err)
+	}
+	if renewal.AutoRenewProductID != "com.example.renewal" {
+		t.Errorf("AutoRenewProductID = %q, want %q", renewal.AutoRenewProductID, "com.example.renewal")
+	}
+}
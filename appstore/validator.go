@@ -19,6 +19,27 @@ const (
 	ContentType string = "application/json; charset=utf-8"
 )
 
+// Env selects which of Apple's two verifyReceipt endpoints a Client talks
+// to first.
+type Env string
+
+const (
+	// EnvAuto always tries production first, since that's what Apple
+	// recommends regardless of where the receipt actually came from, and
+	// lets Verify's 21007/21008 handling switch endpoints as needed.
+	EnvAuto Env = "auto"
+	// EnvProduction always starts against ProductionURL.
+	EnvProduction Env = "production"
+	// EnvSandbox always starts against SandboxURL.
+	EnvSandbox Env = "sandbox"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+)
+
 // IAPClient is an interface to call validation API in App Store
 type IAPClient interface {
 	Verify(ctx context.Context, reqBody IAPRequest, resp interface{}) error
@@ -29,7 +50,25 @@ type Client struct {
 	ProductionURL string
 	SandboxURL    string
 	httpCli       *http.Client
-	IsProduct     bool
+
+	// IsProduct selects the starting endpoint when Env is unset, kept for
+	// backward compatibility with New/NewWithClient callers. New code
+	// should set Env instead.
+	IsProduct bool
+	// Env, if set, takes precedence over IsProduct for the starting
+	// endpoint. Regardless of Env, Verify auto-retries against the other
+	// environment on a 21007/21008 mismatch.
+	Env Env
+
+	// MaxRetries is how many additional attempts Verify makes after an
+	// is-retryable / 21100-21199 response, with exponential backoff
+	// between attempts. Zero uses the package default.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry. Zero uses the
+	// package default.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff delay. Zero uses the package default.
+	MaxBackoff time.Duration
 }
 
 // HandleError returns error message by status code
@@ -98,61 +137,172 @@ func NewWithClient(client *http.Client, isProduct bool) *Client {
 	}
 }
 
-// Verify sends receipts and gets validation result
+// NewWithEnv creates a client object for env, the replacement for the
+// isProduct bool that also supports EnvAuto.
+func NewWithEnv(env Env) *Client {
+	return &Client{
+		ProductionURL: ProductionURL,
+		SandboxURL:    SandboxURL,
+		Env:           env,
+		httpCli: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// NewWithEnvAndClient creates a client for env with a custom http client.
+func NewWithEnvAndClient(client *http.Client, env Env) *Client {
+	return &Client{
+		ProductionURL: ProductionURL,
+		SandboxURL:    SandboxURL,
+		Env:           env,
+		httpCli:       client,
+	}
+}
+
+// startEnv resolves the endpoint Verify should try first, preferring Env
+// over the legacy IsProduct bool, treating EnvAuto as "try production
+// first," and falling back to IsProduct when Env is unset.
+func (c *Client) startEnv() Env {
+	switch c.Env {
+	case EnvProduction, EnvSandbox:
+		return c.Env
+	case EnvAuto:
+		return EnvProduction
+	default:
+		if c.IsProduct {
+			return EnvProduction
+		}
+		return EnvSandbox
+	}
+}
+
+func (c *Client) urlFor(env Env) string {
+	if env == EnvSandbox {
+		return c.SandboxURL
+	}
+	return c.ProductionURL
+}
+
+func (c *Client) maxRetries() int {
+	if c.MaxRetries > 0 {
+		return c.MaxRetries
+	}
+	return defaultMaxRetries
+}
+
+func (c *Client) initialBackoff() time.Duration {
+	if c.InitialBackoff > 0 {
+		return c.InitialBackoff
+	}
+	return defaultInitialBackoff
+}
+
+func (c *Client) maxBackoff() time.Duration {
+	if c.MaxBackoff > 0 {
+		return c.MaxBackoff
+	}
+	return defaultMaxBackoff
+}
+
+// Verify sends receipts and gets validation result. It starts against the
+// endpoint chosen by startEnv, automatically retries once against the
+// other environment on a 21007/21008 mismatch, and retries with
+// exponential backoff (up to MaxRetries) on an is-retryable or
+// 21100-21199 response, all while respecting ctx cancellation.
 func (c *Client) Verify(ctx context.Context, reqBody IAPRequest, result interface{}) error {
+	env := c.startEnv()
+	swapped := false
+
+	backoff := c.initialBackoff()
+	retries := 0
+	for {
+		status, err := c.verifyOnce(ctx, c.urlFor(env), reqBody, result)
+		if err != nil {
+			return err
+		}
+
+		if !swapped && (status.Status == 21007 || status.Status == 21008) {
+			env = otherEnv(env)
+			swapped = true
+			continue
+		}
+
+		if isRetryable(status) && retries < c.maxRetries() {
+			retries++
+			if err := sleep(ctx, backoff); err != nil {
+				return err
+			}
+			backoff *= 2
+			if max := c.maxBackoff(); backoff > max {
+				backoff = max
+			}
+			continue
+		}
+
+		return nil
+	}
+}
+
+func otherEnv(env Env) Env {
+	if env == EnvSandbox {
+		return EnvProduction
+	}
+	return EnvSandbox
+}
+
+func isRetryable(status StatusResponse) bool {
+	return status.IsRetryable || (status.Status >= 21100 && status.Status <= 21199)
+}
+
+// verifyOnce issues a single request against url and decodes the response
+// into result, returning the parsed status so Verify can decide whether
+// to retry.
+func (c *Client) verifyOnce(ctx context.Context, url string, reqBody IAPRequest, result interface{}) (StatusResponse, error) {
 	b := new(bytes.Buffer)
-	json.NewEncoder(b).Encode(reqBody)
+	if err := json.NewEncoder(b).Encode(reqBody); err != nil {
+		return StatusResponse{}, err
+	}
 
-	req, err := http.NewRequest("POST", c.ProductionURL, b)
+	req, err := http.NewRequest("POST", url, b)
 	if err != nil {
-		return err
+		return StatusResponse{}, err
 	}
 	req.Header.Set("Content-Type", ContentType)
 	req = req.WithContext(ctx)
+
 	resp, err := c.httpCli.Do(req)
 	if err != nil {
-		return err
+		return StatusResponse{}, err
 	}
 	defer resp.Body.Close()
-	return c.parseResponse(resp, result, ctx, reqBody)
-}
 
-func (c *Client) parseResponse(resp *http.Response, result interface{}, ctx context.Context, reqBody IAPRequest) error {
-	// Read the body now so that we can unmarshal it twice
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return StatusResponse{}, err
 	}
 
-	err = json.Unmarshal(buf, &result)
-	if err != nil {
-		return err
+	if err := json.Unmarshal(buf, result); err != nil {
+		return StatusResponse{}, err
 	}
 
-	// https://developer.apple.com/library/content/technotes/tn2413/_index.html#//apple_ref/doc/uid/DTS40016228-CH1-RECEIPTURL
-	var r StatusResponse
-	err = json.Unmarshal(buf, &r)
-	if err != nil {
-		return err
+	var status StatusResponse
+	if err := json.Unmarshal(buf, &status); err != nil {
+		return StatusResponse{}, err
 	}
-	if c.IsProduct == false && r.Status == 21007 {
-		b := new(bytes.Buffer)
-		json.NewEncoder(b).Encode(reqBody)
 
-		req, err := http.NewRequest("POST", c.SandboxURL, b)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Content-Type", ContentType)
-		req = req.WithContext(ctx)
-		resp, err := c.httpCli.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
+	return status, nil
+}
 
-		return json.NewDecoder(resp.Body).Decode(result)
-	}
+// sleep waits for d, or returns ctx.Err() if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	return nil
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
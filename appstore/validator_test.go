@@ -0,0 +1,141 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(handler http.HandlerFunc) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	c := &Client{
+		ProductionURL:  server.URL,
+		SandboxURL:     server.URL + "/sandbox",
+		httpCli:        server.Client(),
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+	}
+	return c, server
+}
+
+func TestVerify_EnvSwapOnMismatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		startEnv    Env
+		firstStatus int
+		wantPath    string
+	}{
+		{
+			name:        "21007 swaps production to sandbox",
+			startEnv:    EnvProduction,
+			firstStatus: 21007,
+			wantPath:    "/sandbox",
+		},
+		{
+			name:        "21008 swaps sandbox to production",
+			startEnv:    EnvSandbox,
+			firstStatus: 21008,
+			wantPath:    "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			var secondPath string
+			c, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				status := 0
+				if n == 1 {
+					status = tt.firstStatus
+				} else {
+					secondPath = r.URL.Path
+				}
+				json.NewEncoder(w).Encode(StatusResponse{Status: status})
+			})
+			defer server.Close()
+			c.Env = tt.startEnv
+
+			var result IAPResponse
+			if err := c.Verify(context.Background(), IAPRequest{}, &result); err != nil {
+				t.Fatalf("Verify() unexpected error: %v", err)
+			}
+			if got := atomic.LoadInt32(&calls); got != 2 {
+				t.Fatalf("request count = %d, want 2 (initial + one swap)", got)
+			}
+			if secondPath != tt.wantPath {
+				t.Errorf("second request path = %q, want %q", secondPath, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestVerify_EnvSwapsOnlyOnce(t *testing.T) {
+	var calls int32
+	c, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		// Always returns the mismatch status; Verify must not swap forever.
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(StatusResponse{Status: 21007})
+	})
+	defer server.Close()
+	c.Env = EnvProduction
+	c.MaxRetries = 0
+
+	var result IAPResponse
+	if err := c.Verify(context.Background(), IAPRequest{}, &result); err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("request count = %d, want 2 (initial + single swap, no further swaps)", got)
+	}
+}
+
+func TestVerify_RetriesUntilMaxRetriesWithBackoff(t *testing.T) {
+	var calls int32
+	c, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(StatusResponse{IsRetryable: true})
+	})
+	defer server.Close()
+	c.Env = EnvProduction
+	c.MaxRetries = 3
+
+	start := time.Now()
+	var result IAPResponse
+	if err := c.Verify(context.Background(), IAPRequest{}, &result); err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if got := atomic.LoadInt32(&calls); got != int32(c.MaxRetries)+1 {
+		t.Fatalf("request count = %d, want %d (initial + MaxRetries retries)", got, c.MaxRetries+1)
+	}
+	// Backoff is 1ms, 2ms, 4ms (capped at MaxBackoff=4ms): at least 7ms total.
+	if elapsed < 7*time.Millisecond {
+		t.Errorf("elapsed = %v, want at least 7ms of backoff", elapsed)
+	}
+}
+
+func TestVerify_ContextCancelledDuringBackoff(t *testing.T) {
+	c, server := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(StatusResponse{IsRetryable: true})
+	})
+	defer server.Close()
+	c.Env = EnvProduction
+	c.InitialBackoff = 50 * time.Millisecond
+	c.MaxBackoff = 50 * time.Millisecond
+	c.MaxRetries = 5
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var result IAPResponse
+	err := c.Verify(ctx, IAPRequest{}, &result)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Verify() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}